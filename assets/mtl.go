@@ -0,0 +1,104 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package assets
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mobile/f32"
+)
+
+// parseMTL parses a Wavefront .mtl file into a map keyed by material
+// name. A nil or empty mtl returns an empty map, so callers can treat
+// "no material file" the same as "material file yields the zero
+// Material".
+func parseMTL(mtl []byte) (map[string]Material, error) {
+	materials := map[string]Material{}
+	if len(mtl) == 0 {
+		return materials, nil
+	}
+
+	var (
+		cur     Material
+		haveCur bool
+	)
+	flush := func() {
+		if haveCur {
+			materials[cur.Name] = cur
+		}
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(string(mtl)))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "newmtl":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("assets: bad newmtl line: %q", sc.Text())
+			}
+			flush()
+			cur = Material{Name: fields[1]}
+			haveCur = true
+		case "Ka":
+			c, err := parseColor(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("assets: bad Ka line: %v", err)
+			}
+			cur.Ka = c
+		case "Kd":
+			c, err := parseColor(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("assets: bad Kd line: %v", err)
+			}
+			cur.Kd = c
+		case "Ks":
+			c, err := parseColor(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("assets: bad Ks line: %v", err)
+			}
+			cur.Ks = c
+		case "Ns":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("assets: bad Ns line: %q", sc.Text())
+			}
+			n, err := strconv.ParseFloat(fields[1], 32)
+			if err != nil {
+				return nil, fmt.Errorf("assets: bad Ns line: %v", err)
+			}
+			cur.Ns = float32(n)
+		case "map_Kd":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("assets: bad map_Kd line: %q", sc.Text())
+			}
+			cur.MapKd = fields[len(fields)-1]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return materials, nil
+}
+
+func parseColor(f []string) (f32.Vec4, error) {
+	if len(f) < 3 {
+		return f32.Vec4{}, fmt.Errorf("expected 3 components, got %d", len(f))
+	}
+	var v [3]float64
+	for i := 0; i < 3; i++ {
+		var err error
+		v[i], err = strconv.ParseFloat(f[i], 32)
+		if err != nil {
+			return f32.Vec4{}, err
+		}
+	}
+	return f32.Vec4{float32(v[0]), float32(v[1]), float32(v[2]), 1}, nil
+}
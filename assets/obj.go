@@ -0,0 +1,356 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package assets parses Wavefront .obj model files and their companion
+// .mtl material files into meshes that can be uploaded to the GPU at
+// startup. It replaces the old gengopher go:generate step, which baked
+// flate-compressed vertex data directly into Go source: with this
+// package a model can be swapped out by dropping new .obj/.mtl files
+// into an app's assets, no regeneration required.
+package assets
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mobile/f32"
+)
+
+// Mesh is one named group of triangles decoded from an .obj file,
+// together with the material it was painted with. Vertices is an
+// interleaved [position(3), normal(3), uv(2)] array, one entry per
+// unique vertex; Indices draws triangles from it three at a time.
+// Corners that repeat the same position, normal and uv are merged
+// into a single entry during loading.
+type Mesh struct {
+	Name string
+
+	Vertices []float32
+	Indices  []uint16
+
+	Material Material
+}
+
+// vertexStride is the number of float32s per entry in Mesh.Vertices:
+// 3 position + 3 normal + 2 uv.
+const vertexStride = 8
+
+// Material is the subset of a Wavefront .mtl entry this package
+// understands: the ambient, diffuse and specular colors, the specular
+// exponent, and an optional diffuse texture map.
+type Material struct {
+	Name string
+
+	Ka f32.Vec4 // ambient color
+	Kd f32.Vec4 // diffuse color
+	Ks f32.Vec4 // specular color
+	Ns float32  // specular exponent (shininess)
+
+	MapKd string // diffuse texture file name, relative to the MTL file
+}
+
+// smoothVertex identifies a (smoothing group, source vertex) pair whose
+// face normals should be averaged together.
+type smoothVertex struct {
+	group int
+	index int
+}
+
+// vertexKey identifies a unique (position, uv, normal-source) corner
+// within one mesh, for deduplication into an index buffer. Explicit vn
+// indices and smoothed (group, v) pairs dedupe across every face that
+// shares them; flat-shaded corners (no vn, smoothing off) are keyed by
+// their triangle too, since their normal is that triangle's alone.
+type vertexKey struct {
+	v, vt, vn int
+	smooth    smoothVertex
+	tri       int
+}
+
+// Load parses an .obj file and its companion .mtl file, returning one
+// Mesh per g/o group (further split on usemtl changes within a group).
+// The mtl argument may be nil, in which case every Mesh gets a
+// Material that is zero apart from the Name its usemtl line gave it.
+// Shared vertices are deduplicated into Mesh.Indices.
+func Load(obj, mtl []byte) ([]*Mesh, error) {
+	materials, err := parseMTL(mtl)
+	if err != nil {
+		return nil, fmt.Errorf("assets: parsing mtl: %v", err)
+	}
+
+	var (
+		positions [][3]float32
+		uvs       [][2]float32
+		normals   [][3]float32
+
+		group     = "default"
+		material  string
+		smoothGrp int
+		triCount  int
+
+		meshes []*Mesh
+		cur    *Mesh
+		curIdx map[vertexKey]uint16
+	)
+
+	// accum holds the running sum of face normals contributed to each
+	// smoothed vertex, keyed by (smoothing group, source position). The
+	// averaged result is written back into Vertices once the whole
+	// file has been read.
+	accum := map[smoothVertex]*f32.Vec3{}
+	type patch struct {
+		mesh *Mesh
+		at   int // index into mesh.Vertices of the normal's first component
+		key  smoothVertex
+	}
+	var patches []patch
+
+	newMesh := func() {
+		cur = &Mesh{Name: group, Material: materials[material]}
+		cur.Material.Name = material
+		meshes = append(meshes, cur)
+		curIdx = map[vertexKey]uint16{}
+	}
+	newMesh()
+
+	// addVertex returns the index of the unique vertex described by key,
+	// appending a new interleaved entry to cur.Vertices the first time
+	// key is seen.
+	addVertex := func(key vertexKey, p [3]float32, vn int, faceNormal [3]float32, uv [2]float32) (uint16, error) {
+		if idx, ok := curIdx[key]; ok {
+			return idx, nil
+		}
+		if len(cur.Vertices)/vertexStride >= 1<<16 {
+			return 0, fmt.Errorf("mesh %q exceeds 65536 unique vertices", cur.Name)
+		}
+		idx := uint16(len(cur.Vertices) / vertexStride)
+		curIdx[key] = idx
+
+		var n [3]float32
+		switch {
+		case vn >= 0:
+			n = normals[vn]
+		case smoothGrp == 0:
+			n = faceNormal
+		default:
+			// n is filled in below, once every face sharing key.smooth
+			// has contributed its normal to accum; see the patches
+			// write-back at the end of Load.
+			patches = append(patches, patch{cur, len(cur.Vertices) + 3, key.smooth})
+		}
+		cur.Vertices = append(cur.Vertices, p[0], p[1], p[2], n[0], n[1], n[2], uv[0], uv[1])
+		return idx, nil
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(string(obj)))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			x, y, z, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("assets: bad v line %q: %v", sc.Text(), err)
+			}
+			positions = append(positions, [3]float32{x, y, z})
+		case "vt":
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("assets: bad vt line: %q", sc.Text())
+			}
+			u, err := strconv.ParseFloat(fields[1], 32)
+			if err != nil {
+				return nil, err
+			}
+			v, err := strconv.ParseFloat(fields[2], 32)
+			if err != nil {
+				return nil, err
+			}
+			uvs = append(uvs, [2]float32{float32(u), float32(v)})
+		case "vn":
+			x, y, z, err := parseVec3(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("assets: bad vn line %q: %v", sc.Text(), err)
+			}
+			normals = append(normals, [3]float32{x, y, z})
+		case "g", "o":
+			if len(fields) > 1 {
+				group = fields[1]
+			}
+			if len(cur.Vertices) > 0 {
+				newMesh()
+			} else {
+				cur.Name = group
+			}
+		case "usemtl":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("assets: bad usemtl line: %q", sc.Text())
+			}
+			material = fields[1]
+			if len(cur.Vertices) > 0 {
+				newMesh()
+			} else {
+				cur.Material = materials[material]
+				cur.Material.Name = material
+			}
+		case "s":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("assets: bad s line: %q", sc.Text())
+			}
+			if fields[1] == "off" || fields[1] == "0" {
+				smoothGrp = 0
+			} else {
+				n, err := strconv.Atoi(fields[1])
+				if err != nil {
+					return nil, fmt.Errorf("assets: bad s line: %q", sc.Text())
+				}
+				smoothGrp = n
+			}
+		case "f":
+			corners := fields[1:]
+			if len(corners) < 3 {
+				return nil, fmt.Errorf("assets: face with fewer than 3 vertices: %q", sc.Text())
+			}
+			vi := make([]int, len(corners))
+			ti := make([]int, len(corners))
+			ni := make([]int, len(corners))
+			for i, c := range corners {
+				var err error
+				vi[i], ti[i], ni[i], err = parseFaceVertex(c, len(positions), len(uvs), len(normals))
+				if err != nil {
+					return nil, fmt.Errorf("assets: bad f line %q: %v", sc.Text(), err)
+				}
+			}
+			// Fan-triangulate polygons with more than 3 vertices.
+			for i := 1; i < len(corners)-1; i++ {
+				tri := [3]int{0, i, i + 1}
+				fn := faceNormal(positions[vi[tri[0]]], positions[vi[tri[1]]], positions[vi[tri[2]]])
+				triCount++
+				for _, c := range tri {
+					var uv [2]float32
+					if ti[c] >= 0 {
+						uv = uvs[ti[c]]
+					}
+					key := vertexKey{v: vi[c], vt: ti[c], vn: ni[c], smooth: smoothVertex{smoothGrp, vi[c]}}
+					if ni[c] < 0 && smoothGrp == 0 {
+						// Flat shading: each triangle's normal is its own,
+						// so corners at the same position must not merge
+						// across different triangles.
+						key.tri = triCount
+					}
+					if ni[c] < 0 && smoothGrp != 0 {
+						// Accumulate every triangle touching this smoothed
+						// vertex, not just the one that happens to insert
+						// it into cur.Vertices: later triangles sharing
+						// key.smooth dedup against the first in addVertex,
+						// so their normals are summed here instead.
+						sum, ok := accum[key.smooth]
+						if !ok {
+							sum = &f32.Vec3{}
+							accum[key.smooth] = sum
+						}
+						sum[0] += fn[0]
+						sum[1] += fn[1]
+						sum[2] += fn[2]
+					}
+					idx, err := addVertex(key, positions[vi[c]], ni[c], fn, uv)
+					if err != nil {
+						return nil, err
+					}
+					cur.Indices = append(cur.Indices, idx)
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range patches {
+		n := normalize(*accum[p.key])
+		p.mesh.Vertices[p.at+0] = n[0]
+		p.mesh.Vertices[p.at+1] = n[1]
+		p.mesh.Vertices[p.at+2] = n[2]
+	}
+
+	out := meshes[:0]
+	for _, m := range meshes {
+		if len(m.Vertices) > 0 {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func parseVec3(f []string) (x, y, z float32, err error) {
+	if len(f) < 3 {
+		return 0, 0, 0, fmt.Errorf("expected 3 components, got %d", len(f))
+	}
+	var vals [3]float64
+	for i := 0; i < 3; i++ {
+		vals[i], err = strconv.ParseFloat(f[i], 32)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return float32(vals[0]), float32(vals[1]), float32(vals[2]), nil
+}
+
+// parseFaceVertex parses one "v", "v/vt" or "v/vt/vn" face corner,
+// resolving negative (relative-to-end-of-list) indices per the OBJ
+// spec, and returns 0-based indices. A missing vt or vn is reported
+// as -1.
+func parseFaceVertex(s string, nv, nvt, nvn int) (v, vt, vn int, err error) {
+	parts := strings.Split(s, "/")
+	v, err = parseIndex(parts[0], nv)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	vt, vn = -1, -1
+	if len(parts) > 1 && parts[1] != "" {
+		if vt, err = parseIndex(parts[1], nvt); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		if vn, err = parseIndex(parts[2], nvn); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return v, vt, vn, nil
+}
+
+func parseIndex(s string, n int) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return n + i, nil
+	}
+	return i - 1, nil
+}
+
+func faceNormal(a, b, c [3]float32) [3]float32 {
+	u := [3]float32{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+	v := [3]float32{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+	n := [3]float32{
+		u[1]*v[2] - u[2]*v[1],
+		u[2]*v[0] - u[0]*v[2],
+		u[0]*v[1] - u[1]*v[0],
+	}
+	return normalize(n)
+}
+
+func normalize(v [3]float32) [3]float32 {
+	l := float32(math.Sqrt(float64(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])))
+	if l == 0 {
+		return v
+	}
+	return [3]float32{v[0] / l, v[1] / l, v[2] / l}
+}
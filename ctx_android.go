@@ -0,0 +1,16 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build android
+
+package main
+
+import "github.com/crawshaw/gopher3d/gles"
+
+// On Android, draw the gopher through gles.Native instead of the
+// default gles.Mobile, bypassing golang.org/x/mobile/gl's per-call
+// channel send in the render loop.
+func init() {
+	ctx = gles.Native()
+}
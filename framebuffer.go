@@ -0,0 +1,65 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/mobile/gl"
+)
+
+// Framebuffer is an offscreen render target with a single
+// DEPTH_COMPONENT texture attachment and no color buffer: exactly what
+// the shadow map's depth pre-pass needs to render into, and nothing
+// more. GLES2 has no glDrawBuffer to disable color output the way
+// desktop GL does; leaving the color attachment off entirely has the
+// same effect.
+type Framebuffer struct {
+	fb    gl.Framebuffer
+	Depth gl.Texture
+	Size  int
+}
+
+// NewDepthFramebuffer allocates a size x size depth-only framebuffer.
+func NewDepthFramebuffer(size int) (*Framebuffer, error) {
+	depth := gl.GenTexture()
+	gl.BindTexture(gl.TEXTURE_2D, depth)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, size, size, gl.DEPTH_COMPONENT, gl.UNSIGNED_SHORT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	fb := gl.GenFramebuffer()
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, depth, 0)
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, gl.Framebuffer{})
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		return nil, fmt.Errorf("shadow map framebuffer incomplete: %v", status)
+	}
+
+	return &Framebuffer{fb: fb, Depth: depth, Size: size}, nil
+}
+
+// Bind makes f the current render target and resizes the viewport to
+// match it. Callers must set the viewport back afterward.
+//
+// This runs once per frame, alongside the draw calls in drawDepthPass,
+// so it goes through ctx rather than gl directly: on the Native
+// backend, gl.* would hit golang.org/x/mobile/gl's separate worker
+// goroutine and could reorder against the ctx.* calls bracketing it.
+func (f *Framebuffer) Bind() {
+	ctx.BindFramebuffer(gl.FRAMEBUFFER, f.fb)
+	ctx.Viewport(0, 0, f.Size, f.Size)
+}
+
+// Unbind restores the default (window) framebuffer and sets the
+// viewport back to width x height. See the note on Bind about why
+// this goes through ctx.
+func Unbind(width, height int) {
+	ctx.BindFramebuffer(gl.FRAMEBUFFER, gl.Framebuffer{})
+	ctx.Viewport(0, 0, width, height)
+}
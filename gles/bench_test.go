@@ -0,0 +1,91 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gles
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/f32"
+	"golang.org/x/mobile/gl"
+)
+
+// fakeContext is a no-op Context used only to benchmark the fixed
+// cost of dispatching gopher3d's per-frame call sequence through the
+// Context interface, independent of any real GPU or RPC layer. It
+// does not by itself demonstrate the win Native has over Mobile --
+// that only shows up on an Android device, where Mobile pays for a
+// channel send to the GL worker goroutine per call and Native doesn't
+// -- but it does confirm that routing calls through Context adds no
+// measurable dispatch overhead of its own, so swapping backends is
+// free.
+type fakeContext struct{}
+
+func (fakeContext) UseProgram(Program)                                       {}
+func (fakeContext) BindBuffer(gl.Enum, Buffer)                               {}
+func (fakeContext) EnableVertexAttribArray(Attrib)                           {}
+func (fakeContext) DisableVertexAttribArray(Attrib)                          {}
+func (fakeContext) VertexAttribPointer(Attrib, int, gl.Enum, bool, int, int) {}
+func (fakeContext) Uniform1i(Uniform, int)                                   {}
+func (fakeContext) Uniform1f(Uniform, float32)                               {}
+func (fakeContext) Uniform3f(Uniform, float32, float32, float32)             {}
+func (fakeContext) WriteMat4(Uniform, *f32.Mat4)                             {}
+func (fakeContext) WriteVec4(Uniform, *f32.Vec4)                             {}
+func (fakeContext) ActiveTexture(gl.Enum)                                    {}
+func (fakeContext) BindTexture(gl.Enum, Texture)                             {}
+func (fakeContext) DrawArrays(gl.Enum, int, int)                             {}
+func (fakeContext) DrawElements(gl.Enum, int, gl.Enum, int)                  {}
+
+func (fakeContext) Clear(gl.Enum)                                 {}
+func (fakeContext) ClearColor(float32, float32, float32, float32) {}
+func (fakeContext) Enable(gl.Enum)                                {}
+func (fakeContext) Disable(gl.Enum)                               {}
+func (fakeContext) CullFace(gl.Enum)                              {}
+func (fakeContext) BindFramebuffer(gl.Enum, Framebuffer)          {}
+func (fakeContext) Viewport(int, int, int, int)                   {}
+
+// BenchmarkDrawGophers issues a shortened form of the Context calls
+// drawColorPass makes for one piece, gophers times, simulating that
+// many copies of the gopher drawn in a single frame. It leaves out the
+// lightViewProj, light, specular and shadow-map uniforms.
+//
+// Run on a workstation against fakeContext, as here, it only measures
+// the cost of going through the Context interface -- useful to check
+// that cost is negligible, but not what this backend split is for. To
+// see the actual RPC-avoidance win, build for android with -tags
+// android and run the equivalent benchmark on-device once with ctx
+// set to gles.Mobile() and once with gles.Native(): the gap between
+// them is the per-call channel send this package exists to skip.
+func BenchmarkDrawGophers(b *testing.B) {
+	var ctx Context = fakeContext{}
+	const gophers = 50
+
+	var view, model, projection f32.Mat4
+	var ambient, diffuse f32.Vec4
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for g := 0; g < gophers; g++ {
+			ctx.UseProgram(Program{})
+			ctx.WriteMat4(Uniform{}, &projection)
+			ctx.WriteMat4(Uniform{}, &view)
+			ctx.WriteMat4(Uniform{}, &model)
+			ctx.WriteVec4(Uniform{}, &ambient)
+			ctx.WriteVec4(Uniform{}, &diffuse)
+			ctx.Uniform1f(Uniform{}, 32)
+
+			ctx.EnableVertexAttribArray(Attrib{})
+			ctx.EnableVertexAttribArray(Attrib{})
+			ctx.BindBuffer(gl.ARRAY_BUFFER, Buffer{})
+			ctx.VertexAttribPointer(Attrib{}, 3, gl.FLOAT, false, 32, 0)
+			ctx.VertexAttribPointer(Attrib{}, 3, gl.FLOAT, false, 32, 12)
+
+			ctx.BindTexture(gl.TEXTURE_2D, Texture{})
+			ctx.DrawElements(gl.TRIANGLES, 300, gl.UNSIGNED_SHORT, 0)
+
+			ctx.DisableVertexAttribArray(Attrib{})
+			ctx.DisableVertexAttribArray(Attrib{})
+		}
+	}
+}
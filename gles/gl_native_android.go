@@ -0,0 +1,139 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build android
+
+package gles
+
+/*
+#cgo LDFLAGS: -lGLESv2
+#include <GLES2/gl2.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"golang.org/x/mobile/f32"
+	"golang.org/x/mobile/gl"
+)
+
+// nativeContext implements Context by calling libGLESv2.so directly
+// through cgo, skipping the worker-goroutine channel
+// golang.org/x/mobile/gl uses to serialize every call. Its handles
+// (Buffer, Program, Attrib, Uniform, Texture) are still the ones
+// golang.org/x/mobile/gl handed out during one-time setup -- buffer
+// upload, program linking and texture creation all still go through
+// gl.*, per the package doc -- so nativeContext only needs to turn
+// their Value fields back into the GLuint/GLint the real entry points
+// expect.
+type nativeContext struct{}
+
+// Native returns a Context that calls libGLESv2.so directly via cgo.
+// It only makes sense on Android, where the shared library is
+// guaranteed to be present; main chooses it over Mobile in an
+// android-tagged init.
+func Native() Context { return nativeContext{} }
+
+func (nativeContext) UseProgram(p Program) {
+	C.glUseProgram(C.GLuint(p.Value))
+}
+
+func (nativeContext) BindBuffer(target gl.Enum, b Buffer) {
+	C.glBindBuffer(C.GLenum(target), C.GLuint(b.Value))
+}
+
+func (nativeContext) EnableVertexAttribArray(a Attrib) {
+	C.glEnableVertexAttribArray(C.GLuint(a.Value))
+}
+
+func (nativeContext) DisableVertexAttribArray(a Attrib) {
+	C.glDisableVertexAttribArray(C.GLuint(a.Value))
+}
+
+func (nativeContext) VertexAttribPointer(a Attrib, size int, ty gl.Enum, normalized bool, stride, offset int) {
+	var norm C.GLboolean
+	if normalized {
+		norm = 1
+	}
+	// offset is a byte offset into the currently bound ARRAY_BUFFER,
+	// not a real pointer; encoding it as one in the low bits of the
+	// argument is what glVertexAttribPointer has always expected.
+	C.glVertexAttribPointer(C.GLuint(a.Value), C.GLint(size), C.GLenum(ty), norm, C.GLsizei(stride), unsafe.Pointer(uintptr(offset)))
+}
+
+func (nativeContext) Uniform1i(u Uniform, v int) {
+	C.glUniform1i(C.GLint(u.Value), C.GLint(v))
+}
+
+func (nativeContext) Uniform1f(u Uniform, v float32) {
+	C.glUniform1f(C.GLint(u.Value), C.GLfloat(v))
+}
+
+func (nativeContext) Uniform3f(u Uniform, x, y, z float32) {
+	C.glUniform3f(C.GLint(u.Value), C.GLfloat(x), C.GLfloat(y), C.GLfloat(z))
+}
+
+func (nativeContext) WriteMat4(u Uniform, m *f32.Mat4) {
+	// m is row-major; GLES2 requires transpose == GL_FALSE, so transpose
+	// it into column-major here ourselves, the same as
+	// golang.org/x/mobile/gl's Uniform.WriteMat4 does for the Mobile
+	// backend, rather than passing transpose == GL_TRUE (not allowed) or
+	// the raw row-major data (wrong).
+	var a [16]C.GLfloat
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			a[j*4+i] = C.GLfloat(m[i][j])
+		}
+	}
+	C.glUniformMatrix4fv(C.GLint(u.Value), 1, 0, &a[0])
+}
+
+func (nativeContext) WriteVec4(u Uniform, v *f32.Vec4) {
+	C.glUniform4f(C.GLint(u.Value), C.GLfloat(v[0]), C.GLfloat(v[1]), C.GLfloat(v[2]), C.GLfloat(v[3]))
+}
+
+func (nativeContext) ActiveTexture(texture gl.Enum) {
+	C.glActiveTexture(C.GLenum(texture))
+}
+
+func (nativeContext) BindTexture(target gl.Enum, t Texture) {
+	C.glBindTexture(C.GLenum(target), C.GLuint(t.Value))
+}
+
+func (nativeContext) DrawArrays(mode gl.Enum, first, count int) {
+	C.glDrawArrays(C.GLenum(mode), C.GLint(first), C.GLsizei(count))
+}
+
+func (nativeContext) DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int) {
+	C.glDrawElements(C.GLenum(mode), C.GLsizei(count), C.GLenum(ty), unsafe.Pointer(uintptr(offset)))
+}
+
+func (nativeContext) Clear(mask gl.Enum) {
+	C.glClear(C.GLbitfield(mask))
+}
+
+func (nativeContext) ClearColor(r, g, b, a float32) {
+	C.glClearColor(C.GLfloat(r), C.GLfloat(g), C.GLfloat(b), C.GLfloat(a))
+}
+
+func (nativeContext) Enable(cap gl.Enum) {
+	C.glEnable(C.GLenum(cap))
+}
+
+func (nativeContext) Disable(cap gl.Enum) {
+	C.glDisable(C.GLenum(cap))
+}
+
+func (nativeContext) CullFace(mode gl.Enum) {
+	C.glCullFace(C.GLenum(mode))
+}
+
+func (nativeContext) BindFramebuffer(target gl.Enum, fb Framebuffer) {
+	C.glBindFramebuffer(C.GLenum(target), C.GLuint(fb.Value))
+}
+
+func (nativeContext) Viewport(x, y, width, height int) {
+	C.glViewport(C.GLint(x), C.GLint(y), C.GLsizei(width), C.GLsizei(height))
+}
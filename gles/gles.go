@@ -0,0 +1,75 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gles abstracts the GL calls gopher3d issues every frame
+// behind a small interface, so the render loop can run against either
+// golang.org/x/mobile/gl -- which serializes every call onto a worker
+// goroutine over a channel, a well-known hot-path cost on Android --
+// or, on Android, a cgo binding straight into libGLESv2.so that skips
+// that channel entirely.
+//
+// Only the per-frame hot path is covered: one-time setup calls
+// (texture upload, framebuffer creation, program linking, attribute
+// and uniform location lookups) aren't worth the RPC-avoidance
+// complexity and keep calling golang.org/x/mobile/gl directly.
+package gles
+
+import (
+	"golang.org/x/mobile/f32"
+	"golang.org/x/mobile/gl"
+)
+
+// Buffer, Program, Attrib, Uniform, Texture and Framebuffer are the GL
+// object handle types Context methods take and return. They are the
+// same types golang.org/x/mobile/gl uses, so a Context backed by
+// Mobile can work directly with handles main.go already obtained from
+// gl.GenBuffer and friends during setup.
+type (
+	Buffer      = gl.Buffer
+	Program     = gl.Program
+	Attrib      = gl.Attrib
+	Uniform     = gl.Uniform
+	Texture     = gl.Texture
+	Framebuffer = gl.Framebuffer
+)
+
+// Context is the subset of GL entry points gopher3d's render loop
+// calls once per piece, or once per pass, per frame. It covers the
+// draw calls themselves as well as the state changes interleaved with
+// them (clears, face culling, framebuffer/viewport swaps): on the
+// Native backend those calls hit libGLESv2.so directly and on the
+// same goroutine as the draw calls, so leaving any of them to call
+// golang.org/x/mobile/gl directly -- a separate worker goroutine --
+// would let the two backends reorder relative to each other within a
+// frame.
+type Context interface {
+	UseProgram(p Program)
+
+	BindBuffer(target gl.Enum, b Buffer)
+
+	EnableVertexAttribArray(a Attrib)
+	DisableVertexAttribArray(a Attrib)
+	VertexAttribPointer(a Attrib, size int, ty gl.Enum, normalized bool, stride, offset int)
+
+	Uniform1i(u Uniform, v int)
+	Uniform1f(u Uniform, v float32)
+	Uniform3f(u Uniform, x, y, z float32)
+	WriteMat4(u Uniform, m *f32.Mat4)
+	WriteVec4(u Uniform, v *f32.Vec4)
+
+	ActiveTexture(texture gl.Enum)
+	BindTexture(target gl.Enum, t Texture)
+
+	DrawArrays(mode gl.Enum, first, count int)
+	DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int)
+
+	Clear(mask gl.Enum)
+	ClearColor(r, g, b, a float32)
+	Enable(cap gl.Enum)
+	Disable(cap gl.Enum)
+	CullFace(mode gl.Enum)
+
+	BindFramebuffer(target gl.Enum, fb Framebuffer)
+	Viewport(x, y, width, height int)
+}
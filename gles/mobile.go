@@ -0,0 +1,52 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gles
+
+import (
+	"golang.org/x/mobile/f32"
+	"golang.org/x/mobile/gl"
+)
+
+// mobileContext implements Context by calling golang.org/x/mobile/gl
+// directly. It is the backend main uses on every platform except
+// Android, where main chooses Native instead.
+type mobileContext struct{}
+
+// Mobile returns a Context backed by golang.org/x/mobile/gl.
+func Mobile() Context { return mobileContext{} }
+
+func (mobileContext) UseProgram(p Program) { gl.UseProgram(p) }
+
+func (mobileContext) BindBuffer(target gl.Enum, b Buffer) { gl.BindBuffer(target, b) }
+
+func (mobileContext) EnableVertexAttribArray(a Attrib)  { gl.EnableVertexAttribArray(a) }
+func (mobileContext) DisableVertexAttribArray(a Attrib) { gl.DisableVertexAttribArray(a) }
+
+func (mobileContext) VertexAttribPointer(a Attrib, size int, ty gl.Enum, normalized bool, stride, offset int) {
+	gl.VertexAttribPointer(a, size, ty, normalized, stride, offset)
+}
+
+func (mobileContext) Uniform1i(u Uniform, v int)           { gl.Uniform1i(u, v) }
+func (mobileContext) Uniform1f(u Uniform, v float32)       { gl.Uniform1f(u, v) }
+func (mobileContext) Uniform3f(u Uniform, x, y, z float32) { gl.Uniform3f(u, x, y, z) }
+func (mobileContext) WriteMat4(u Uniform, m *f32.Mat4)     { u.WriteMat4(m) }
+func (mobileContext) WriteVec4(u Uniform, v *f32.Vec4)     { u.WriteVec4(v) }
+
+func (mobileContext) ActiveTexture(texture gl.Enum)         { gl.ActiveTexture(texture) }
+func (mobileContext) BindTexture(target gl.Enum, t Texture) { gl.BindTexture(target, t) }
+
+func (mobileContext) DrawArrays(mode gl.Enum, first, count int) { gl.DrawArrays(mode, first, count) }
+
+func (mobileContext) DrawElements(mode gl.Enum, count int, ty gl.Enum, offset int) {
+	gl.DrawElements(mode, count, ty, offset)
+}
+
+func (mobileContext) Clear(mask gl.Enum)                             { gl.Clear(mask) }
+func (mobileContext) ClearColor(r, g, b, a float32)                  { gl.ClearColor(r, g, b, a) }
+func (mobileContext) Enable(cap gl.Enum)                             { gl.Enable(cap) }
+func (mobileContext) Disable(cap gl.Enum)                            { gl.Disable(cap) }
+func (mobileContext) CullFace(mode gl.Enum)                          { gl.CullFace(mode) }
+func (mobileContext) BindFramebuffer(target gl.Enum, fb Framebuffer) { gl.BindFramebuffer(target, fb) }
+func (mobileContext) Viewport(x, y, width, height int)               { gl.Viewport(x, y, width, height) }
@@ -8,123 +8,280 @@ package main
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"math"
 
-	"compress/flate"
-
 	"golang.org/x/mobile/app"
 	"golang.org/x/mobile/app/debug"
+	"golang.org/x/mobile/asset"
 	"golang.org/x/mobile/event"
 	"golang.org/x/mobile/f32"
 	"golang.org/x/mobile/geom"
 	"golang.org/x/mobile/gl"
 	"golang.org/x/mobile/gl/glutil"
+
+	"github.com/crawshaw/gopher3d/assets"
+	"github.com/crawshaw/gopher3d/gles"
+	"github.com/crawshaw/gopher3d/scene"
 )
 
-//go:generate go run gengopher.go -output gopher.go -input gopher.obj
+// ctx issues the GL calls the render loop makes every frame. It
+// defaults to gles.Mobile, the golang.org/x/mobile/gl-backed
+// implementation; ctx_android.go swaps in gles.Native, a direct cgo
+// binding to libGLESv2.so, when built for Android. One-time setup in
+// initGL and loadTexture/NewDepthFramebuffer keeps calling gl.*
+// directly, since it isn't the per-frame cost ctx exists to avoid.
+var ctx gles.Context = gles.Mobile()
+
+// gopherObj and gopherMtl name the model shipped in the APK's assets.
+// Swapping the gopher for another model is now a matter of dropping in
+// differently-named files here, with no go:generate step to re-run.
+const (
+	gopherObj = "gopher.obj"
+	gopherMtl = "gopher.mtl"
+)
 
 type piece struct {
-	// flate compressed
-	vertexData []byte
-	normalData []byte
-	color      f32.Vec4
+	// interleaved [position(3), normal(3), uv(2)] per unique vertex,
+	// and the triangle indices that draw them, decoded from the OBJ
+	// file. indexData is nil for a piece with no index data, in which
+	// case draw falls back to DrawArrays.
+	vertexData []float32
+	indexData  []uint16
+
+	// material, decoded from the companion MTL file.
+	ambient   f32.Vec4
+	color     f32.Vec4 // diffuse color
+	specular  f32.Vec4
+	shininess float32
+	mapKd     string
 
 	// populated at GL initialization
-	verticies   gl.Buffer
-	normals     gl.Buffer
+	buf         gl.Buffer
+	indices     gl.Buffer
 	vertexCount int
+	indexCount  int
+	texture     *Texture // non-nil if mapKd named a PNG that decoded
 }
 
-var (
-	gopherSkin = f32.Vec4{0.761, 0.442, 0.180, 1} // brownish
-	gopherFur  = f32.Vec4{0, 0.537, 0.8, 1}       // blue
-	white      = f32.Vec4{1, 1, 1, 1}
-)
+var pieces []*piece
+
+// loadPieces reads the gopher model out of the APK's assets and parses
+// it into the in-memory pieces initGL will upload to the GPU.
+func loadPieces() []*piece {
+	objFile, err := asset.Open(gopherObj)
+	if err != nil {
+		log.Fatalf("gopher3d: opening %s: %v", gopherObj, err)
+	}
+	defer objFile.Close()
+	objData, err := ioutil.ReadAll(objFile)
+	if err != nil {
+		log.Fatalf("gopher3d: reading %s: %v", gopherObj, err)
+	}
+
+	mtlFile, err := asset.Open(gopherMtl)
+	if err != nil {
+		log.Fatalf("gopher3d: opening %s: %v", gopherMtl, err)
+	}
+	defer mtlFile.Close()
+	mtlData, err := ioutil.ReadAll(mtlFile)
+	if err != nil {
+		log.Fatalf("gopher3d: reading %s: %v", gopherMtl, err)
+	}
+
+	meshes, err := assets.Load(objData, mtlData)
+	if err != nil {
+		log.Fatalf("gopher3d: parsing %s: %v", gopherObj, err)
+	}
 
-var pieces = []*piece{
-	{
-		vertexData: Body_Sphere_002,
-		normalData: Body_Sphere_002Normals,
-		color:      gopherFur,
-	},
-	{
-		vertexData: Tail_Sphere_015,
-		normalData: Tail_Sphere_015Normals,
-		color:      gopherSkin,
-	},
-	{
-		vertexData: Foot_R_001_Sphere_014,
-		normalData: Foot_R_001_Sphere_014Normals,
-		color:      gopherSkin,
-	},
-	{
-		vertexData: Foot_R_Sphere_013,
-		normalData: Foot_R_Sphere_013Normals,
-		color:      gopherSkin,
-	},
-	{
-		vertexData: Hnad_L_Sphere_012,
-		normalData: Hnad_L_Sphere_012Normals,
-		color:      gopherSkin,
-	},
-	{
-		vertexData: Hand_R_Sphere_011,
-		normalData: Hand_R_Sphere_011Normals,
-		color:      gopherSkin,
-	},
-	{
-		vertexData: Tooth_Sphere_009,
-		normalData: Tooth_Sphere_009Normals,
-		color:      white,
-	},
-	{
-		vertexData: Ear_R_Sphere_008,
-		normalData: Ear_R_Sphere_008Normals,
-		color:      gopherFur,
-	},
-	{
-		vertexData: Ear_L_Sphere_007,
-		normalData: Ear_L_Sphere_007Normals,
-		color:      gopherFur,
-	},
-	{
-		vertexData: Nose_Sphere,
-		normalData: Nose_SphereNormals,
-		color:      gopherSkin,
-	},
-	{
-		vertexData: Eye_R_Sphere_006,
-		normalData: Eye_R_Sphere_006Normals,
-		color:      white,
-	},
-	{
-		vertexData: Eye_L_Sphere_004,
-		normalData: Eye_L_Sphere_004Normals,
-		color:      white,
-	},
+	ps := make([]*piece, len(meshes))
+	for i, m := range meshes {
+		ps[i] = &piece{
+			vertexData: m.Vertices,
+			indexData:  m.Indices,
+			ambient:    m.Material.Ka,
+			color:      m.Material.Kd,
+			specular:   m.Material.Ks,
+			shininess:  m.Material.Ns,
+			mapKd:      m.Material.MapKd,
+		}
+	}
+	return ps
 }
 
-var (
+// maxLights is the size of the lights array declared in the shaders
+// below; applyLights drops any scene lights beyond this count.
+const maxLights = 4
+
+// lightLocs is one element's uniform locations within a shader's
+// lights[maxLights] array.
+type lightLocs struct {
+	direction gl.Uniform
+	position  gl.Uniform
+	point     gl.Uniform
+	ambient   gl.Uniform
+	diffuse   gl.Uniform
+	specular  gl.Uniform
+}
+
+// shaderProgram bundles a linked GL program with the attribute and
+// uniform locations main.go drives every frame. The flat-color and
+// textured programs have distinct GL programs, and therefore distinct
+// locations, even though most of their uniforms share a name.
+type shaderProgram struct {
 	program gl.Program
 
 	position gl.Attrib
 	normal   gl.Attrib
+	uv       gl.Attrib // textured program only
+
+	materialAmbientFactor  gl.Uniform
+	materialDiffuseFactor  gl.Uniform
+	materialSpecularFactor gl.Uniform
+	materialShininess      gl.Uniform
+
+	lightCount gl.Uniform
+	lights     [maxLights]lightLocs
+
+	model         gl.Uniform
+	view          gl.Uniform
+	projection    gl.Uniform
+	lightViewProj gl.Uniform
+	tex           gl.Uniform // sampler2D, textured program only
+	shadowMap     gl.Uniform // sampler2D
+}
+
+// locate fills in every attribute and uniform location from s.program,
+// which must already be linked. Locations for names the program's
+// shaders don't declare come back as -1 and are simply never written.
+func (s *shaderProgram) locate() {
+	s.position = gl.GetAttribLocation(s.program, "position")
+	s.normal = gl.GetAttribLocation(s.program, "normal")
+	s.uv = gl.GetAttribLocation(s.program, "uv")
+
+	s.materialAmbientFactor = gl.GetUniformLocation(s.program, "materialAmbientFactor")
+	s.materialDiffuseFactor = gl.GetUniformLocation(s.program, "materialDiffuseFactor")
+	s.materialSpecularFactor = gl.GetUniformLocation(s.program, "materialSpecularFactor")
+	s.materialShininess = gl.GetUniformLocation(s.program, "materialShininess")
+
+	s.lightCount = gl.GetUniformLocation(s.program, "lightCount")
+	for i := range s.lights {
+		prefix := fmt.Sprintf("lights[%d].", i)
+		s.lights[i] = lightLocs{
+			direction: gl.GetUniformLocation(s.program, prefix+"direction"),
+			position:  gl.GetUniformLocation(s.program, prefix+"position"),
+			point:     gl.GetUniformLocation(s.program, prefix+"point"),
+			ambient:   gl.GetUniformLocation(s.program, prefix+"ambient"),
+			diffuse:   gl.GetUniformLocation(s.program, prefix+"diffuse"),
+			specular:  gl.GetUniformLocation(s.program, prefix+"specular"),
+		}
+	}
+
+	s.model = gl.GetUniformLocation(s.program, "model")
+	s.view = gl.GetUniformLocation(s.program, "view")
+	s.projection = gl.GetUniformLocation(s.program, "projection")
+	s.lightViewProj = gl.GetUniformLocation(s.program, "lightViewProj")
+	s.tex = gl.GetUniformLocation(s.program, "tex")
+	s.shadowMap = gl.GetUniformLocation(s.program, "shadowMap")
+}
+
+// depthShaderProgram is a minimal GL program with only the attribute
+// and uniforms needed to render pieces' depth from the light's point
+// of view into a Framebuffer, for the color pass to later sample as a
+// shadow map.
+type depthShaderProgram struct {
+	program gl.Program
+
+	position gl.Attrib
+
+	model         gl.Uniform
+	lightViewProj gl.Uniform
+}
+
+func (s *depthShaderProgram) locate() {
+	s.position = gl.GetAttribLocation(s.program, "position")
+	s.model = gl.GetUniformLocation(s.program, "model")
+	s.lightViewProj = gl.GetUniformLocation(s.program, "lightViewProj")
+}
+
+// applyLights writes world.Lights into prog's lights array and
+// lightCount uniforms, truncating to maxLights.
+func applyLights(prog *shaderProgram, lights []scene.Light) {
+	n := len(lights)
+	if n > maxLights {
+		n = maxLights
+	}
+	ctx.Uniform1i(prog.lightCount, n)
+	for i := 0; i < n; i++ {
+		l, loc := lights[i], prog.lights[i]
+		ctx.Uniform3f(loc.direction, l.Direction[0], l.Direction[1], l.Direction[2])
+		ctx.Uniform3f(loc.position, l.Position[0], l.Position[1], l.Position[2])
+		point := float32(0)
+		if l.Point {
+			point = 1
+		}
+		ctx.Uniform1f(loc.point, point)
+		ctx.WriteVec4(loc.ambient, &l.Ambient)
+		ctx.WriteVec4(loc.diffuse, &l.Diffuse)
+		ctx.WriteVec4(loc.specular, &l.Specular)
+	}
+}
 
-	lightDirection        gl.Uniform
-	lightAmbientColor     gl.Uniform
-	lightDiffuseColor     gl.Uniform
-	materialAmbientFactor gl.Uniform
-	materialDiffuseFactor gl.Uniform
-	materialShininess     gl.Uniform
-	model                 gl.Uniform
-	view                  gl.Uniform
-	projection            gl.Uniform
-
-	touchLoc geom.Point
+var (
+	// flatProgram paints a piece with its lit material colors.
+	// texturedProgram multiplies those by a sample of the piece's mapKd
+	// texture; drawColorPass picks one per piece
+	// based on whether p.texture is set. Both sample shadowFB's depth
+	// texture to darken fragments the depth pass determined are
+	// shadowed.
+	flatProgram     shaderProgram
+	texturedProgram shaderProgram
+
+	// depthProgram renders the depth pre-pass; see drawDepthPass.
+	depthProgram depthShaderProgram
 )
 
+// shadowMapSize is the width and height, in texels, of shadowFB's
+// depth texture. Higher resolves shadow edges more finely at the cost
+// of GPU memory and fill rate.
+const shadowMapSize = 1024
+
+// shadowFB is the offscreen depth-only framebuffer the depth pass
+// renders into and the color pass samples as a shadow map.
+var shadowFB *Framebuffer
+
+var (
+	// world holds the scene graph, camera and lights; gopherNode hangs
+	// off world.Root, which is where a future scene would hang
+	// additional objects alongside the gopher.
+	world      = scene.NewScene()
+	gopherNode = scene.NewNode()
+)
+
+func init() {
+	world.Root.Children = append(world.Root.Children, gopherNode)
+	world.Lights = []scene.Light{
+		{
+			Direction: f32.Vec3{.5, .5, 0},
+			Ambient:   f32.Vec4{.5, .5, .5, .5},
+			Diffuse:   f32.Vec4{.8, .8, .8, 1},
+			Specular:  f32.Vec4{1, 1, 1, 1},
+		},
+	}
+}
+
+// touches tracks the last known location of every finger currently
+// down, keyed by its touch sequence ID, so drags and pinches can be
+// measured as deltas between events rather than from an absolute
+// position.
+var touches = map[event.TouchSequenceID]geom.Point{}
+
+// pinchDist is the on-screen distance between the two most recent
+// touches, used to turn a pinch gesture into a zoom delta.
+var pinchDist float32
+
 func main() {
 	app.Run(app.Callbacks{
 		Draw:  draw,
@@ -137,108 +294,274 @@ func initGL() {
 	//gl.Enable(gl.CULL_FACE)
 	//gl.CullFace(gl.BACK)
 
+	pieces = loadPieces()
+
 	var err error
-	program, err = glutil.CreateProgram(vertexShader, fragmentShader)
+	flatProgram.program, err = glutil.CreateProgram(vertexShader, fragmentShader)
 	if err != nil {
 		log.Printf("error creating GL program: %v", err)
 		return
 	}
+	flatProgram.locate()
 
-	for _, p := range pieces {
-		vData := flateBytes(p.vertexData)
-		nData := flateBytes(p.normalData)
-		p.verticies = gl.GenBuffer()
-		p.normals = gl.GenBuffer()
-		// four bytes per float32, three per vertex
-		p.vertexCount = len(vData) / 4 / coordsPerVertex
-
-		gl.BindBuffer(gl.ARRAY_BUFFER, p.verticies)
-		gl.BufferData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, vData)
-		gl.BindBuffer(gl.ARRAY_BUFFER, p.normals)
-		gl.BufferData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, nData)
+	texturedProgram.program, err = glutil.CreateProgram(texturedVertexShader, texturedFragmentShader)
+	if err != nil {
+		log.Printf("error creating textured GL program: %v", err)
+		return
 	}
+	texturedProgram.locate()
 
-	position = gl.GetAttribLocation(program, "position")
-	normal = gl.GetAttribLocation(program, "normal")
+	depthProgram.program, err = glutil.CreateProgram(depthVertexShader, depthFragmentShader)
+	if err != nil {
+		log.Printf("error creating depth GL program: %v", err)
+		return
+	}
+	depthProgram.locate()
 
-	lightDirection = gl.GetUniformLocation(program, "lightDirection")
-	lightAmbientColor = gl.GetUniformLocation(program, "lightAmbientColor")
-	lightDiffuseColor = gl.GetUniformLocation(program, "lightDiffuseColor")
-	materialAmbientFactor = gl.GetUniformLocation(program, "materialAmbientFactor")
-	materialDiffuseFactor = gl.GetUniformLocation(program, "materialDiffuseFactor")
-	model = gl.GetUniformLocation(program, "model")
-	view = gl.GetUniformLocation(program, "view")
-	projection = gl.GetUniformLocation(program, "projection")
+	shadowFB, err = NewDepthFramebuffer(shadowMapSize)
+	if err != nil {
+		log.Printf("gopher3d: %v", err)
+		return
+	}
 
-	initMVP()
+	for _, p := range pieces {
+		p.buf = gl.GenBuffer()
+		p.vertexCount = len(p.vertexData) / vertexStride
+
+		gl.BindBuffer(gl.ARRAY_BUFFER, p.buf)
+		gl.BufferData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, toBytes(p.vertexData))
+
+		if len(p.indexData) > 0 {
+			p.indices = gl.GenBuffer()
+			p.indexCount = len(p.indexData)
+			gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, p.indices)
+			gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, gl.STATIC_DRAW, toShortBytes(p.indexData))
+		}
+
+		if p.mapKd != "" {
+			tex, err := loadTexture(p.mapKd)
+			if err != nil {
+				log.Printf("gopher3d: %v", err)
+				continue
+			}
+			p.texture = tex
+		}
+	}
 }
 
-func initMVP() {
-	gl.UseProgram(program)
+// touch drives the orbit camera: a single finger dragging orbits yaw
+// and pitch around the gopher, and a two-finger pinch zooms.
+func touch(t event.Touch) {
+	switch t.Type {
+	case event.TouchStart:
+		touches[t.ID] = t.Loc
+		if len(touches) == 2 {
+			pinchDist = touchPairDistance()
+		}
+	case event.TouchMove:
+		prev := touches[t.ID]
+		touches[t.ID] = t.Loc
+		switch len(touches) {
+		case 1:
+			dYaw := float32(t.Loc.X-prev.X) / float32(geom.Width) * 2 * float32(math.Pi)
+			dPitch := float32(t.Loc.Y-prev.Y) / float32(geom.Height) * 2 * float32(math.Pi)
+			world.Camera.Orbit(dYaw, dPitch)
+		case 2:
+			d := touchPairDistance()
+			if pinchDist > 0 {
+				world.Camera.Zoom(pinchDist / d)
+			}
+			pinchDist = d
+		}
+	case event.TouchEnd:
+		delete(touches, t.ID)
+		if len(touches) < 2 {
+			pinchDist = 0
+		}
+	}
 }
 
-func touch(t event.Touch) {
-	log.Printf("%s", t)
-	touchLoc = t.Loc
+// touchPairDistance returns the on-screen distance between the two
+// currently-down touches, or 0 if there aren't exactly two.
+func touchPairDistance() float32 {
+	if len(touches) != 2 {
+		return 0
+	}
+	var pts [2]geom.Point
+	i := 0
+	for _, p := range touches {
+		pts[i] = p
+		i++
+	}
+	dx := float32(pts[0].X - pts[1].X)
+	dy := float32(pts[0].Y - pts[1].Y)
+	return float32(math.Sqrt(float64(dx*dx + dy*dy)))
 }
 
 func draw() {
-	if program.Value == 0 {
+	if flatProgram.program.Value == 0 {
 		initGL()
 		log.Printf("example/basic rendering initialized")
 	}
 
-	gl.ClearColor(0, 0, 0, 1)
-	gl.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT)
+	// gopherWorld is gopherNode's world transform; with a single node
+	// under world.Root this is just its own (identity) transform, but
+	// walking the graph is what lets a future sibling object carry a
+	// different one.
+	var gopherWorld f32.Mat4
+	world.Root.Walk(nil, func(n *scene.Node, w f32.Mat4) {
+		if n == gopherNode {
+			gopherWorld = w
+		}
+	})
 
-	gl.UseProgram(program)
+	// lvp is the first light's combined view-projection matrix, used
+	// both to render the depth pre-pass and, in the color pass, to
+	// project each fragment into that pass's shadow map.
+	var lvp f32.Mat4
+	if len(world.Lights) > 0 {
+		lvp = lightViewProj(world.Lights[0], world.Camera.Target)
+	} else {
+		lvp.Identity()
+	}
 
-	frac := float32(touchLoc.X / geom.Width)
-	y := 5 * f32.Sin(2*float32(math.Pi)*frac)
-	z := 5 * f32.Cos(2*float32(math.Pi)*frac)
+	drawDepthPass(lvp, gopherWorld)
+	drawColorPass(lvp, gopherWorld)
 
-	mProj := f32.Mat4{}
-	mProj.Perspective(f32.Radian(math.Pi/4), float32(geom.Width/geom.Height), .1, 200)
-	projection.WriteMat4(&mProj)
+	debug.DrawFPS()
+}
 
-	mView := f32.Mat4{}
-	// Debugging note: pos 0,5,0 leaves you looking right at the gopher
-	mView.LookAt(
-		&f32.Vec3{0, y, -z}, // camera position
-		&f32.Vec3{0, 0, 0},  // camera is pointing at
-		&f32.Vec3{-1, 0, 0}) // rotation
-	view.WriteMat4(&mView)
+// drawDepthPass renders every piece from the light's point of view
+// into shadowFB's depth texture, which drawColorPass then samples as a
+// shadow map. Rendering only back faces (CullFace(FRONT)) pushes the
+// resulting shadow acne onto the model's unlit side, where it isn't
+// visible.
+func drawDepthPass(lvp, gopherWorld f32.Mat4) {
+	shadowFB.Bind()
+	ctx.Clear(gl.DEPTH_BUFFER_BIT)
+	ctx.Enable(gl.CULL_FACE)
+	ctx.CullFace(gl.FRONT)
+
+	ctx.UseProgram(depthProgram.program)
+	ctx.WriteMat4(depthProgram.lightViewProj, &lvp)
+	ctx.WriteMat4(depthProgram.model, &gopherWorld)
+
+	const stride = vertexStride * 4 // bytes per interleaved vertex
+	for _, p := range pieces {
+		ctx.EnableVertexAttribArray(depthProgram.position)
+		ctx.BindBuffer(gl.ARRAY_BUFFER, p.buf)
+		ctx.VertexAttribPointer(depthProgram.position, coordsPerVertex, gl.FLOAT, false, stride, 0)
+
+		if p.indexCount > 0 {
+			ctx.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, p.indices)
+			ctx.DrawElements(gl.TRIANGLES, p.indexCount, gl.UNSIGNED_SHORT, 0)
+		} else {
+			ctx.DrawArrays(gl.TRIANGLES, 0, p.vertexCount)
+		}
+
+		ctx.DisableVertexAttribArray(depthProgram.position)
+	}
 
-	// Gopher model starts on the origin.
-	// Her up is -x, her forward is +z.
-	mModel := f32.Mat4{}
-	mModel.Identity()
+	ctx.Disable(gl.CULL_FACE)
+	Unbind(int(geom.Width.Px(geom.PixelsPerPt)), int(geom.Height.Px(geom.PixelsPerPt)))
+}
 
-	scale := float32(touchLoc.Y/geom.Height + 0.5)
-	mModel.Scale(&mModel, scale, scale, scale)
-	model.WriteMat4(&mModel)
+// drawColorPass renders the scene normally, lighting each piece with
+// world.Lights and darkening fragments drawDepthPass found to be
+// shadowed.
+func drawColorPass(lvp, gopherWorld f32.Mat4) {
+	ctx.ClearColor(0, 0, 0, 1)
+	ctx.Clear(gl.DEPTH_BUFFER_BIT | gl.COLOR_BUFFER_BIT)
 
-	gl.Uniform3f(lightDirection, .5, .5, 0)
-	gl.Uniform4f(materialDiffuseFactor, 0.8, 0.8, 0.8, 1)
-	gl.Uniform4f(materialAmbientFactor, 0.5, 0.5, 0.5, 0.5)
+	mProj := world.Camera.Perspective(float32(geom.Width / geom.Height))
+	mView := world.Camera.LookAt()
 
-	gl.EnableVertexAttribArray(normal)
-	gl.EnableVertexAttribArray(position)
+	const stride = vertexStride * 4 // bytes per interleaved vertex
 	for _, p := range pieces {
-		lightDiffuseColor.WriteVec4(&p.color)
-		lightAmbientColor.WriteVec4(&p.color)
-
-		gl.BindBuffer(gl.ARRAY_BUFFER, p.verticies)
-		gl.VertexAttribPointer(position, coordsPerVertex, gl.FLOAT, false, 0, 0)
-		gl.BindBuffer(gl.ARRAY_BUFFER, p.normals)
-		gl.VertexAttribPointer(normal, coordsPerVertex, gl.FLOAT, false, 0, 0)
+		prog := &flatProgram
+		if p.texture != nil {
+			prog = &texturedProgram
+		}
+		ctx.UseProgram(prog.program)
+
+		ctx.WriteMat4(prog.projection, &mProj)
+		ctx.WriteMat4(prog.view, &mView)
+		ctx.WriteMat4(prog.model, &gopherWorld)
+		ctx.WriteMat4(prog.lightViewProj, &lvp)
+
+		applyLights(prog, world.Lights)
+
+		ctx.WriteVec4(prog.materialAmbientFactor, &p.ambient)
+		ctx.WriteVec4(prog.materialDiffuseFactor, &p.color)
+		ctx.WriteVec4(prog.materialSpecularFactor, &p.specular)
+		ctx.Uniform1f(prog.materialShininess, p.shininess)
+
+		ctx.ActiveTexture(gl.TEXTURE1)
+		ctx.BindTexture(gl.TEXTURE_2D, shadowFB.Depth)
+		ctx.Uniform1i(prog.shadowMap, 1)
+
+		ctx.EnableVertexAttribArray(prog.position)
+		ctx.EnableVertexAttribArray(prog.normal)
+		ctx.BindBuffer(gl.ARRAY_BUFFER, p.buf)
+		ctx.VertexAttribPointer(prog.position, coordsPerVertex, gl.FLOAT, false, stride, 0)
+		ctx.VertexAttribPointer(prog.normal, coordsPerVertex, gl.FLOAT, false, stride, 3*4)
+
+		if p.texture != nil {
+			ctx.EnableVertexAttribArray(prog.uv)
+			ctx.VertexAttribPointer(prog.uv, 2, gl.FLOAT, false, stride, 6*4)
+			ctx.ActiveTexture(gl.TEXTURE0)
+			ctx.BindTexture(gl.TEXTURE_2D, p.texture.id)
+			ctx.Uniform1i(prog.tex, 0)
+		}
+
+		if p.indexCount > 0 {
+			ctx.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, p.indices)
+			ctx.DrawElements(gl.TRIANGLES, p.indexCount, gl.UNSIGNED_SHORT, 0)
+		} else {
+			ctx.DrawArrays(gl.TRIANGLES, 0, p.vertexCount)
+		}
+
+		if p.texture != nil {
+			ctx.DisableVertexAttribArray(prog.uv)
+		}
+		ctx.DisableVertexAttribArray(prog.normal)
+		ctx.DisableVertexAttribArray(prog.position)
+	}
+}
 
-		gl.DrawArrays(gl.TRIANGLES, 0, p.vertexCount)
+// lightViewProj returns the combined projection*view matrix for
+// rendering the depth pre-pass from l's point of view, aimed at
+// target. f32.Mat4 has no orthographic projection, so a directional
+// light is approximated as a point light far enough away (distance)
+// that its perspective frustum behaves like a directional one over
+// the gopher's size.
+func lightViewProj(l scene.Light, target f32.Vec3) f32.Mat4 {
+	const distance = 20
+
+	eye := l.Position
+	if !l.Point {
+		d := l.Direction
+		mag := float32(math.Sqrt(float64(d[0]*d[0] + d[1]*d[1] + d[2]*d[2])))
+		if mag == 0 {
+			mag = 1
+		}
+		eye = f32.Vec3{
+			target[0] + d[0]/mag*distance,
+			target[1] + d[1]/mag*distance,
+			target[2] + d[2]/mag*distance,
+		}
 	}
-	gl.DisableVertexAttribArray(normal)
-	gl.DisableVertexAttribArray(position)
+	up := f32.Vec3{0, 1, 0}
 
-	debug.DrawFPS()
+	var view f32.Mat4
+	view.LookAt(&eye, &target, &up)
+
+	var proj f32.Mat4
+	proj.Perspective(f32.Radian(math.Pi/4), 1, 1, 100)
+
+	var vp f32.Mat4
+	vp.Mul(&proj, &view)
+	return vp
 }
 
 func toBytes(v []float32) []byte {
@@ -249,53 +572,263 @@ func toBytes(v []float32) []byte {
 	return buf.Bytes()
 }
 
-func flateBytes(v []byte) []byte {
-	b, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(v)))
-	if err != nil {
+func toShortBytes(v []uint16) []byte {
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
 		log.Fatal(err)
 	}
-	return b
+	return buf.Bytes()
 }
 
 const coordsPerVertex = 3
 
+// vertexStride is the number of float32s per interleaved vertex entry
+// in piece.vertexData: 3 position + 3 normal + 2 uv. It must match
+// assets.Mesh's layout.
+const vertexStride = 8
+
+// vertexShader computes everything in eye space (view * model), the
+// way the original flat-color shader already did: it interpolates the
+// eye-space position and normal per-fragment instead of shading
+// per-vertex, so fragmentShader below can do full Phong lighting,
+// including a specular term, on the gopher's rounded surfaces.
 const vertexShader = `
-uniform vec3 lightDirection;
-uniform vec4 lightAmbientColor;
-uniform vec4 lightDiffuseColor;
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 projection;
+uniform mat4 lightViewProj;
+
+attribute vec4 position;
+attribute vec3 normal;
+
+varying vec3 vPosition;
+varying vec3 vNormal;
+varying vec4 vShadowCoord;
+
+void main() {
+	mat4 mv = view * model;
+	vec4 eyePosition = mv * position;
+
+	vPosition = eyePosition.xyz;
+	vNormal = mat3(mv) * normal;
+	vShadowCoord = lightViewProj * model * position;
+
+	gl_Position = projection * eyePosition;
+}
+`
+
+// fragmentShader shades in eye space, where the camera always sits at
+// the origin: that makes the view vector for the specular term just
+// normalize(-vPosition), with no inverse view matrix required. Each
+// light's direction or position is carried in world space and rotated
+// into eye space by view, the same as the vertex shader does for
+// normals. shadowVisibility samples shadowMap, the depth pre-pass'
+// output, to darken the diffuse and specular terms for fragments the
+// light can't see.
+const fragmentShader = `
+precision mediump float;
+
+struct Light {
+	vec3 direction; // world space, used when point == 0.0
+	vec3 position;  // world space, used when point == 1.0
+	float point;
+	vec4 ambient;
+	vec4 diffuse;
+	vec4 specular;
+};
+
+const int maxLights = 4;
+uniform Light lights[maxLights];
+uniform int lightCount;
+
+uniform mat4 view;
 
 uniform vec4 materialAmbientFactor;
 uniform vec4 materialDiffuseFactor;
+uniform vec4 materialSpecularFactor;
+uniform float materialShininess;
+
+uniform sampler2D shadowMap;
+
+varying vec3 vPosition;
+varying vec3 vNormal;
+varying vec4 vShadowCoord;
+
+// shadowVisibility returns 1.0 for a lit fragment and 0.5 for one the
+// depth pre-pass found something closer to the light, given shadowPos
+// in lightViewProj clip space. Fragments that land outside the light's
+// frustum are treated as lit, since the shadow map says nothing about
+// them.
+float shadowVisibility(vec4 shadowPos) {
+	vec3 p = shadowPos.xyz / shadowPos.w;
+	p = p * 0.5 + 0.5; // clip space [-1,1] to texture/depth space [0,1]
+	if (p.x < 0.0 || p.x > 1.0 || p.y < 0.0 || p.y > 1.0 || p.z > 1.0) {
+		return 1.0;
+	}
+	const float bias = 0.005;
+	float closest = texture2D(shadowMap, p.xy).r;
+	return p.z > closest + bias ? 0.5 : 1.0;
+}
 
+void main() {
+	vec3 n = normalize(vNormal);
+	vec3 v = normalize(-vPosition);
+	float visibility = shadowVisibility(vShadowCoord);
+
+	vec4 color = vec4(0.0);
+	for (int i = 0; i < maxLights; i++) {
+		if (i >= lightCount) {
+			break;
+		}
+
+		vec3 l;
+		if (lights[i].point > 0.5) {
+			vec3 lightPosition = vec3(view * vec4(lights[i].position, 1.0));
+			l = normalize(lightPosition - vPosition);
+		} else {
+			l = normalize(mat3(view) * lights[i].direction);
+		}
+		vec3 r = reflect(-l, n);
+
+		float diffuseTerm = max(dot(n, l), 0.0);
+		float specularTerm = 0.0;
+		if (diffuseTerm > 0.0) {
+			specularTerm = pow(max(dot(r, v), 0.0), materialShininess);
+		}
+
+		color += lights[i].ambient * materialAmbientFactor;
+		color += visibility * diffuseTerm * lights[i].diffuse * materialDiffuseFactor;
+		color += visibility * specularTerm * lights[i].specular * materialSpecularFactor;
+	}
+	gl_FragColor = color;
+}`
+
+const texturedVertexShader = `
 uniform mat4 model;
 uniform mat4 view;
 uniform mat4 projection;
+uniform mat4 lightViewProj;
 
 attribute vec4 position;
 attribute vec3 normal;
+attribute vec2 uv;
 
-varying vec4 color;
+varying vec3 vPosition;
+varying vec3 vNormal;
+varying vec2 vUV;
+varying vec4 vShadowCoord;
 
 void main() {
 	mat4 mv = view * model;
-	mat4 mvp = projection * mv;
+	vec4 eyePosition = mv * position;
+
+	vPosition = eyePosition.xyz;
+	vNormal = mat3(mv) * normal;
+	vUV = uv;
+	vShadowCoord = lightViewProj * model * position;
+
+	gl_Position = projection * eyePosition;
+}
+`
+
+const texturedFragmentShader = `
+precision mediump float;
 
-	vec3 eyespace = vec3(mv * vec4(normal, 0.0));
-	eyespace = eyespace / length(eyespace);
+struct Light {
+	vec3 direction;
+	vec3 position;
+	float point;
+	vec4 ambient;
+	vec4 diffuse;
+	vec4 specular;
+};
 
-	float direction = max(0.0, dot(eyespace, lightDirection));
+const int maxLights = 4;
+uniform Light lights[maxLights];
+uniform int lightCount;
 
-	vec4 ambient = lightAmbientColor * materialAmbientFactor;
-	vec4 diffuse = direction * lightDiffuseColor * materialDiffuseFactor;
+uniform mat4 view;
 
-	color = ambient + diffuse;
-	gl_Position = mvp * position;
+uniform vec4 materialAmbientFactor;
+uniform vec4 materialDiffuseFactor;
+uniform vec4 materialSpecularFactor;
+uniform float materialShininess;
+
+uniform sampler2D tex;
+uniform sampler2D shadowMap;
+
+varying vec3 vPosition;
+varying vec3 vNormal;
+varying vec2 vUV;
+varying vec4 vShadowCoord;
+
+// shadowVisibility is the same technique as in fragmentShader; see
+// that copy for the rationale.
+float shadowVisibility(vec4 shadowPos) {
+	vec3 p = shadowPos.xyz / shadowPos.w;
+	p = p * 0.5 + 0.5;
+	if (p.x < 0.0 || p.x > 1.0 || p.y < 0.0 || p.y > 1.0 || p.z > 1.0) {
+		return 1.0;
+	}
+	const float bias = 0.005;
+	float closest = texture2D(shadowMap, p.xy).r;
+	return p.z > closest + bias ? 0.5 : 1.0;
+}
+
+void main() {
+	vec3 n = normalize(vNormal);
+	vec3 v = normalize(-vPosition);
+	float visibility = shadowVisibility(vShadowCoord);
+
+	vec4 color = vec4(0.0);
+	for (int i = 0; i < maxLights; i++) {
+		if (i >= lightCount) {
+			break;
+		}
+
+		vec3 l;
+		if (lights[i].point > 0.5) {
+			vec3 lightPosition = vec3(view * vec4(lights[i].position, 1.0));
+			l = normalize(lightPosition - vPosition);
+		} else {
+			l = normalize(mat3(view) * lights[i].direction);
+		}
+		vec3 r = reflect(-l, n);
+
+		float diffuseTerm = max(dot(n, l), 0.0);
+		float specularTerm = 0.0;
+		if (diffuseTerm > 0.0) {
+			specularTerm = pow(max(dot(r, v), 0.0), materialShininess);
+		}
+
+		color += lights[i].ambient * materialAmbientFactor;
+		color += visibility * diffuseTerm * lights[i].diffuse * materialDiffuseFactor;
+		color += visibility * specularTerm * lights[i].specular * materialSpecularFactor;
+	}
+	gl_FragColor = color * texture2D(tex, vUV);
+}`
+
+// depthVertexShader projects each vertex into the light's clip space
+// instead of the camera's, so depthFragmentShader can rasterize its
+// depth into shadowFB.
+const depthVertexShader = `
+uniform mat4 model;
+uniform mat4 lightViewProj;
+
+attribute vec4 position;
+
+void main() {
+	gl_Position = lightViewProj * model * position;
 }
 `
 
-const fragmentShader = `
+// depthFragmentShader writes no visible color: shadowFB has no color
+// attachment to write it into. GLES2 still requires every fragment
+// shader to assign gl_FragColor, so this is a placeholder; only the
+// rasterized depth buffer is read back, by shadowMap in fragmentShader
+// and texturedFragmentShader.
+const depthFragmentShader = `
 precision mediump float;
-varying vec4 color;
 void main() {
-	gl_FragColor = color;
+	gl_FragColor = vec4(1.0);
 }`
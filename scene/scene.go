@@ -0,0 +1,173 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package scene provides a small scene graph and an orbiting camera,
+// replacing the touch-to-matrix math that used to live directly in
+// main's draw function.
+package scene
+
+import (
+	"math"
+
+	"golang.org/x/mobile/f32"
+)
+
+// Node is a transform and its children. A renderer walks the graph
+// with Walk to get each node's world (accumulated-parent) transform,
+// which it uploads as the model matrix before drawing whatever
+// geometry the node represents.
+type Node struct {
+	Name      string
+	Transform f32.Mat4
+	Children  []*Node
+}
+
+// NewNode returns a Node with an identity transform.
+func NewNode() *Node {
+	n := &Node{}
+	n.Transform.Identity()
+	return n
+}
+
+// World returns n's transform composed with its parent's world
+// transform. Pass nil for a root node.
+func (n *Node) World(parent *f32.Mat4) f32.Mat4 {
+	if parent == nil {
+		return n.Transform
+	}
+	var world f32.Mat4
+	world.Mul(parent, &n.Transform)
+	return world
+}
+
+// Walk visits n and every descendant depth-first, calling visit with
+// each node's world transform.
+func (n *Node) Walk(parent *f32.Mat4, visit func(n *Node, world f32.Mat4)) {
+	world := n.World(parent)
+	visit(n, world)
+	for _, c := range n.Children {
+		c.Walk(&world, visit)
+	}
+}
+
+// Light is a single light source, either directional (Point false,
+// Direction pointing toward the light, of any non-zero length) or positional
+// (Point true, Position in world space). Ambient, Diffuse and Specular
+// are the light's contribution to each term of the Phong lighting
+// equation.
+type Light struct {
+	Direction f32.Vec3
+	Position  f32.Vec3
+	Point     bool
+
+	Ambient  f32.Vec4
+	Diffuse  f32.Vec4
+	Specular f32.Vec4
+}
+
+// Scene bundles the object graph, camera and lights that make up one
+// frame's worth of drawable state.
+type Scene struct {
+	Root   *Node
+	Camera *Camera
+	Lights []Light
+}
+
+// NewScene returns an empty Scene: an identity root node and a camera
+// framing the origin from 5 units away, with no lights.
+func NewScene() *Scene {
+	return &Scene{
+		Root:   NewNode(),
+		Camera: NewCamera(),
+	}
+}
+
+// Camera is an orbit/arcball camera: it always looks at Target from a
+// point Distance away, reached by rotating Yaw around Target's up
+// axis and Pitch above/below the horizon.
+type Camera struct {
+	Target   f32.Vec3
+	Up       f32.Vec3
+	Yaw      float32 // radians, around Up
+	Pitch    float32 // radians, clamped to avoid flipping over the pole
+	Distance float32
+
+	FOV, Near, Far float32
+}
+
+// NewCamera returns a Camera framing the origin from 5 units away on
+// the +z axis, with the up axis, field of view and clip planes the
+// gopher's view previously used.
+func NewCamera() *Camera {
+	return &Camera{
+		Up:       f32.Vec3{-1, 0, 0}, // the gopher model's up is -x
+		Distance: 5,
+		FOV:      float32(math.Pi / 4),
+		Near:     .1,
+		Far:      200,
+	}
+}
+
+// maxPitch keeps the camera from swinging past the pole, where yaw
+// becomes degenerate.
+const maxPitch = float32(math.Pi/2) - 0.01
+
+// Orbit adjusts yaw and pitch by the given deltas, typically derived
+// from a single-finger touch drag.
+func (c *Camera) Orbit(dYaw, dPitch float32) {
+	c.Yaw += dYaw
+	c.Pitch += dPitch
+	if c.Pitch > maxPitch {
+		c.Pitch = maxPitch
+	}
+	if c.Pitch < -maxPitch {
+		c.Pitch = -maxPitch
+	}
+}
+
+// Zoom scales Distance by factor, typically derived from a two-finger
+// pinch, and keeps it within a sane range of the target.
+func (c *Camera) Zoom(factor float32) {
+	c.Distance *= factor
+	switch {
+	case c.Distance < 1:
+		c.Distance = 1
+	case c.Distance > 50:
+		c.Distance = 50
+	}
+}
+
+// eye returns the camera's position on the orbit sphere around Target.
+// Pitch is elevation toward Up (-x, the gopher model's up axis, not
+// +y), so Yaw spins the camera around Up in the y-z plane instead of
+// around +y in the x-z plane: the pole where LookAt's forward and up
+// vectors coincide (and its cross product, hence its view matrix,
+// degenerates) then falls at Pitch = ±maxPitch, which is already
+// clamped, rather than at the unguarded Yaw = ±π/2, Pitch = 0.
+func (c *Camera) eye() f32.Vec3 {
+	x := -c.Distance * f32.Sin(c.Pitch)
+	r := c.Distance * f32.Cos(c.Pitch)
+	y := r * f32.Sin(c.Yaw)
+	z := r * f32.Cos(c.Yaw)
+	return f32.Vec3{c.Target[0] + x, c.Target[1] + y, c.Target[2] + z}
+}
+
+// LookAt returns the view matrix for the camera's current orbit
+// position.
+func (c *Camera) LookAt() f32.Mat4 {
+	eye := c.eye()
+	center := c.Target
+	up := c.Up
+	var m f32.Mat4
+	m.LookAt(&eye, &center, &up)
+	return m
+}
+
+// Perspective returns the projection matrix for the camera's field of
+// view at the given viewport aspect ratio.
+func (c *Camera) Perspective(aspect float32) f32.Mat4 {
+	var m f32.Mat4
+	m.Perspective(f32.Radian(c.FOV), aspect, c.Near, c.Far)
+	return m
+}
@@ -0,0 +1,49 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+
+	"golang.org/x/mobile/asset"
+	"golang.org/x/mobile/gl"
+)
+
+// Texture is a PNG image uploaded to the GPU as an RGBA 2D texture.
+type Texture struct {
+	id gl.Texture
+}
+
+// loadTexture decodes the named PNG from the APK's assets and uploads
+// it as a gl.TEXTURE_2D.
+func loadTexture(name string) (*Texture, error) {
+	f, err := asset.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("texture: opening %s: %v", name, err)
+	}
+	defer f.Close()
+
+	src, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("texture: decoding %s: %v", name, err)
+	}
+
+	b := src.Bounds()
+	rgba := image.NewRGBA(b)
+	draw.Draw(rgba, b, src, b.Min, draw.Src)
+
+	id := gl.GenTexture()
+	gl.BindTexture(gl.TEXTURE_2D, id)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, b.Dx(), b.Dy(), gl.RGBA, gl.UNSIGNED_BYTE, rgba.Pix)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	return &Texture{id: id}, nil
+}